@@ -15,6 +15,34 @@ func (m *snakeTest) platina1RURoute() {
 	}
 }
 
+// Backplane cabling from the chassis topology below: spine port for each port < 16 of leaf th0 and th1.
+var platina1RULeafToSpine = [2][16]int{
+	{23, 15, 16, 17, 18, 19, 20, 21, 22, 8, 9, 10, 11, 12, 13, 14},
+	{1, 2, 3, 4, 5, 6, 7, 25, 26, 27, 28, 29, 30, 31, 0, 24},
+}
+
+const platina1RUSpineIndex = 2
+
+func (m *snakeTest) platina1RUPeer(p *port) *port {
+	d := &m.devs[p.dev_index]
+	switch {
+	case d.role == ROLE_SPINE:
+		for li := range platina1RULeafToSpine {
+			for lpi, spi := range platina1RULeafToSpine[li] {
+				if spi == p.port_index {
+					return m.portAt(uint8(li), lpi)
+				}
+			}
+		}
+		return nil
+	case p.port_index < 16:
+		return m.portAt(platina1RUSpineIndex, platina1RULeafToSpine[d.index][p.port_index])
+	default:
+		// Front panel ports are cabled in pairs 16 <-> 17, 18 <-> 19, ...
+		return m.portAt(d.index, p.port_index^1)
+	}
+}
+
 // Routing table derivation.
 // for leaf ths
 //   if pi < 16 => pi -> pi + 1
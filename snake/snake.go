@@ -23,6 +23,7 @@ import (
 
 	"flag"
 	"fmt"
+	"os"
 	"runtime"
 	"sort"
 	"strings"
@@ -91,6 +92,7 @@ type dev struct {
 	counterIndex map[string]int
 	portConfig   C.bcm_port_config_t
 	socInfo      *C.soc_info_t
+	nTxFrames    int // frames injected by tx
 }
 
 type counterCode int
@@ -98,6 +100,11 @@ type counterCode int
 const (
 	TBYT counterCode = iota
 	TPKT
+	RPKT
+	RFCS
+	RUND
+	ROVR
+	RERPKT
 	Max
 )
 
@@ -107,6 +114,16 @@ func (c counterCode) String() string {
 		return "TBYT"
 	case TPKT:
 		return "TPKT"
+	case RPKT:
+		return "RPKT"
+	case RFCS:
+		return "RFCS"
+	case RUND:
+		return "RUND"
+	case ROVR:
+		return "ROVR"
+	case RERPKT:
+		return "RERPKT"
 	case Max:
 		return ""
 	default:
@@ -144,12 +161,15 @@ type port struct {
 	vrf           C.bcm_vrf_t
 	vlanID        C.bcm_vlan_t
 	name          string
+	loopback      string
 	counters      []counter
 	counterByCode []*counter
 }
 
 type snakeTest struct {
 	cfg           config
+	loopbacks     map[string]string
+	usedLoopbacks map[string]bool
 	devs          []dev
 	indexByUnit   []int
 	unitByIndex   []int
@@ -340,7 +360,12 @@ func (m *snakeTest) initPort(p *port) (err error) {
 		}
 		i.action_mask |= C.BCM_PORT_ATTR_INTERFACE_MASK
 
-		switch m.cfg.loopback {
+		p.loopback = m.cfg.loopback
+		if v, ok := m.loopbacks[p.name]; ok {
+			p.loopback = v
+			m.usedLoopbacks[p.name] = true
+		}
+		switch p.loopback {
 		case "phy":
 			i.loopback = C.BCM_PORT_LOOPBACK_PHY
 		case "mac":
@@ -348,7 +373,7 @@ func (m *snakeTest) initPort(p *port) (err error) {
 		case "none":
 			i.loopback = C.BCM_PORT_LOOPBACK_NONE
 		default:
-			panic(fmt.Errorf("unkown loopback %s", m.cfg.loopback))
+			panic(fmt.Errorf("unkown loopback %s", p.loopback))
 		}
 		i.action_mask |= C.BCM_PORT_ATTR_LOOPBACK_MASK
 
@@ -635,10 +660,32 @@ func (m *snakeTest) tx(d *dev, nPacket int) (err error) {
 		if err != nil {
 			return
 		}
+		d.nTxFrames++
 	}
 	return
 }
 
+// Port cabled to p, or nil if p isn't cabled to another test port.
+// Off the chassis, front-panel cables pair port 2n with port 2n+1.
+func (m *snakeTest) cabledPeer(p *port) *port {
+	if m.cfg.isPlatinaChassis {
+		return m.platina1RUPeer(p)
+	}
+	return m.portAt(m.devs[p.dev_index].index, p.port_index^1)
+}
+
+// Port by device index (not unit) and port index, or nil if there is no such port.
+func (m *snakeTest) portAt(devIndex uint8, portIndex int) *port {
+	if int(devIndex) >= len(m.unitByIndex) {
+		return nil
+	}
+	d := &m.devs[m.unitByIndex[devIndex]]
+	if portIndex >= len(d.ports) {
+		return nil
+	}
+	return &d.ports[portIndex]
+}
+
 func (m *snakeTest) printLinkStates() (nUp int) {
 	nUp = 0
 	for di := range m.devs {
@@ -678,6 +725,12 @@ func run(c config) (err error) {
 	}
 	m.packetBuffer = layer.Make(m.eth, m.ip4, m.payload)
 
+	m.loopbacks, err = parsePortLoopback(c.portLoopback)
+	if err != nil {
+		return
+	}
+	m.usedLoopbacks = make(map[string]bool)
+
 	// Configure SDK
 	{
 		// These maps are for backplane cables and fp cables wired port <-> port+1
@@ -860,6 +913,34 @@ func run(c config) (err error) {
 		}
 	}
 
+	// Overrides not used during port init name ports that don't exist.
+	// Port names depend on the port bitmap, so this can't be checked before cold boot.
+	if len(m.usedLoopbacks) != len(m.loopbacks) {
+		var names []string
+		for name := range m.loopbacks {
+			if !m.usedLoopbacks[name] {
+				names = append(names, name)
+			}
+		}
+		sort.Strings(names)
+		err = fmt.Errorf("port-loopback: no such ports: %s", strings.Join(names, ", "))
+		return
+	}
+
+	// A port out of loopback only links up (and stays on the snake path) if its cabled partner is too.
+	for di := range m.devs {
+		for pi := range m.devs[di].ports {
+			p := &m.devs[di].ports[pi]
+			if p.loopback != "none" {
+				continue
+			}
+			if q := m.cabledPeer(p); q != nil && q.loopback != "none" {
+				err = fmt.Errorf("port-loopback: %s is none but its cabled partner %s is %s", p.name, q.name, q.loopback)
+				return
+			}
+		}
+	}
+
 	// Map unique ID to port
 	m.ports_by_uid = make([]*port, int(nPortUID))
 	for di := range m.devs {
@@ -895,12 +976,17 @@ func run(c config) (err error) {
 	}
 
 	// Debug - print link state
+	tLinkTimeout := time.Now().Add(m.cfg.LinkTimeout)
 	for {
 		fmt.Printf("Linkstates check\n")
 		n_up := m.printLinkStates()
 		if n_up == len(m.ports_by_uid) {
 			break
 		}
+		if time.Now().After(tLinkTimeout) {
+			err = fmt.Errorf("only %d of %d ports up after %v", n_up, len(m.ports_by_uid), m.cfg.LinkTimeout)
+			return
+		}
 		time.Sleep(100 * time.Millisecond)
 	}
 
@@ -1011,21 +1097,110 @@ func run(c config) (err error) {
 		}
 	}
 
+	err = m.portReport()
+	if err != nil {
+		return
+	}
+
 	fmt.Println("Done\n")
 
 	return
 }
 
+// Per-port pass/fail: a port passes if it sent packets, received all but those still in flight,
+// and saw no receive errors.
+func (m *snakeTest) portReport() (err error) {
+	// A port's rx may trail its tx by the frames circulating in its ring.
+	// Each device is its own ring, except on the chassis where one ring spans all devices.
+	nChassisFrames := 0
+	for di := range m.devs {
+		nChassisFrames += m.devs[di].nTxFrames
+	}
+	nFail := 0
+	fmt.Printf("%-8s%-10s%16s%16s%10s%10s%10s%10s  %s\n",
+		"Port", "Loopback", "Tx packets", "Rx packets", "FCS", "Undersize", "Oversize", "Rx errors", "Result")
+	for di := range m.devs {
+		d := &m.devs[di]
+		inFlight := uint64(d.nTxFrames)
+		if m.cfg.isPlatinaChassis {
+			inFlight = uint64(nChassisFrames)
+		}
+		for pi := range d.ports {
+			p := &d.ports[pi]
+			tx := p.counterByCode[TPKT].value
+			rx := p.counterByCode[RPKT].value
+			fcs := p.counterByCode[RFCS].value
+			und := p.counterByCode[RUND].value
+			ovr := p.counterByCode[ROVR].value
+			rerr := p.counterByCode[RERPKT].value
+			result := "pass"
+			if tx == 0 || rx+inFlight < tx || fcs+und+ovr+rerr != 0 {
+				result = "FAIL"
+				nFail++
+			}
+			fmt.Printf("%-8s%-10s%16d%16d%10d%10d%10d%10d  %s\n",
+				p.name, p.loopback, tx, rx, fcs, und, ovr, rerr, result)
+		}
+	}
+	if nFail > 0 {
+		err = &portTestError{nFail: nFail, nPort: len(m.ports_by_uid)}
+	}
+	return
+}
+
+// Returned when the test ran to completion but some ports failed.
+type portTestError struct {
+	nFail, nPort int
+}
+
+func (e *portTestError) Error() string {
+	return fmt.Sprintf("%d of %d ports failed", e.nFail, e.nPort)
+}
+
+// Parse per-port loopback overrides of the form port=mode[,port=mode...].
+func parsePortLoopback(s string) (lb map[string]string, err error) {
+	lb = make(map[string]string)
+	if s == "" {
+		return
+	}
+	for _, f := range strings.Split(s, ",") {
+		kv := strings.SplitN(f, "=", 2)
+		if len(kv) != 2 {
+			err = fmt.Errorf("port-loopback: expected port=mode, got %q", f)
+			return
+		}
+		name, mode := strings.TrimSpace(kv[0]), strings.TrimSpace(kv[1])
+		if name == "" {
+			err = fmt.Errorf("port-loopback: missing port name in %q", f)
+			return
+		}
+		switch mode {
+		case "phy", "mac", "none":
+		default:
+			err = fmt.Errorf("port-loopback: unknown loopback %s for port %s", mode, name)
+			return
+		}
+		if _, ok := lb[name]; ok {
+			err = fmt.Errorf("port-loopback: port %s given more than once", name)
+			return
+		}
+		lb[name] = mode
+	}
+	return
+}
+
 type config struct {
 	verbose          bool
 	isPlatinaChassis bool
 	isLayer3         bool
 	loopback         string
+	portLoopback     string
 	nFrames          int
 	frameSize        int
 	// public so %+v prints as 10s instead of in nsec
 	TestDuration  time.Duration
 	StatsDuration time.Duration
+	LinkTimeout   time.Duration
 }
 
 func main() {
@@ -1035,16 +1210,22 @@ func main() {
 	flag.BoolVar(&c.isLayer3, "layer3", false, "Snake Test with Layer3")
 	flag.DurationVar(&c.TestDuration, "time", 10*time.Second, "Snake test duration")
 	flag.DurationVar(&c.StatsDuration, "print", 5*time.Second, "Statistics print interval")
+	flag.DurationVar(&c.LinkTimeout, "link-timeout", 30*time.Second, "Time to wait for all ports to link up")
 	flag.IntVar(&c.nFrames, "n-frames", 10, "Number of frames to send")
 	flag.IntVar(&c.frameSize, "frame-size", 9416, "Ethernet frame size (0 means use max)")
 	flag.BoolVar(&c.verbose, "verbose", false, "Verbose output")
 	flag.StringVar(&c.loopback, "loopback", "phy", "Put ports into loopback (none, phy, mac)")
+	flag.StringVar(&c.portLoopback, "port-loopback", "", "Per-port loopback overrides (e.g. ce0.3=mac,ce2.5=none)")
 	flag.Parse()
 
 	if c.verbose {
 		fmt.Printf("Config: %+v\n", c)
 	}
 	e := run(c)
+	if f, ok := e.(*portTestError); ok {
+		fmt.Println(f)
+		os.Exit(1)
+	}
 	if e != nil {
 		panic(e)
 	}
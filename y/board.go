@@ -0,0 +1,74 @@
+package main
+
+import (
+	"github.com/platinasystems/go/vnet"
+	"github.com/platinasystems/go/vnet/platforms/fe1"
+
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// A board brings up and tears down the switch platform for one hardware design.
+// Boards register themselves from an init function in their own file under a
+// board name, along with the system EEPROM product names that identify them.
+// Port init, GPIO map and SR-IOV layout are still handled by the platform
+// packages themselves; they become methods here as those move out of mk1.
+type board interface {
+	Init(v *vnet.Vnet, p *fe1.Platform) error
+	Exit(v *vnet.Vnet, p *fe1.Platform) error
+}
+
+// Board used when the EEPROM product name can't be read or isn't registered.
+// mk1 is the only board so far; drop the fallback once a second one exists.
+const defaultBoard = "mk1"
+
+var (
+	boards        = make(map[string]board)
+	boardProducts = make(map[string]string) // eeprom product name -> board name
+)
+
+func boardKey(id string) string { return strings.ToLower(strings.TrimSpace(id)) }
+
+func registerBoard(name string, b board, products ...string) {
+	k := boardKey(name)
+	if _, ok := boards[k]; ok {
+		panic(fmt.Errorf("board %s already registered", k))
+	}
+	boards[k] = b
+	for _, p := range products {
+		if o, ok := boardProducts[boardKey(p)]; ok {
+			panic(fmt.Errorf("product %s already registered to board %s", p, o))
+		}
+		boardProducts[boardKey(p)] = k
+	}
+}
+
+func knownBoards() (names []string) {
+	for n := range boards {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return
+}
+
+// Select board by name when given (wip board NAME), otherwise by EEPROM product name.
+func selectBoard(name, product string) (b board, err error) {
+	k := boardKey(name)
+	if k == "" {
+		var ok bool
+		if k, ok = boardProducts[boardKey(product)]; !ok {
+			k = defaultBoard
+			if product == "" {
+				fmt.Printf("eeprom product name unavailable; using default board %s\n", k)
+			} else {
+				fmt.Printf("eeprom product %q not recognized; using default board %s\n", product, k)
+			}
+		}
+	}
+	b, ok := boards[k]
+	if !ok {
+		err = fmt.Errorf("unknown board %s; known boards: %v", k, knownBoards())
+	}
+	return
+}
@@ -0,0 +1,18 @@
+package main
+
+import (
+	"github.com/platinasystems/go/vnet"
+	"github.com/platinasystems/go/vnet/platforms/fe1"
+	"github.com/platinasystems/go/vnet/platforms/mk1"
+)
+
+type mk1Board struct{}
+
+func (mk1Board) Init(v *vnet.Vnet, p *fe1.Platform) error { return mk1.PlatformInit(v, p) }
+func (mk1Board) Exit(v *vnet.Vnet, p *fe1.Platform) error { return mk1.PlatformExit(v, p) }
+
+// No EEPROM product names are registered for mk1 yet; mk1 units are picked
+// up as the default board until they are.
+func init() {
+	registerBoard("mk1", mk1Board{})
+}
@@ -6,28 +6,50 @@ import (
 	"github.com/platinasystems/go/vnet"
 	"github.com/platinasystems/go/vnet/ethernet"
 	"github.com/platinasystems/go/vnet/platforms/fe1"
-	"github.com/platinasystems/go/vnet/platforms/mk1"
 
 	"fmt"
 	"os"
 )
 
-func platformConfigFromEEPROM(p *fe1.Platform) {
+// Configure platform from system EEPROM and return the board's product name,
+// or "" when the EEPROM cannot be read (the default board is then used).
+func platformConfigFromEEPROM(p *fe1.Platform) (product string) {
 	d := eeprom.Device{
 		BusIndex:   0,
 		BusAddress: 0x51,
 	}
 	if e := d.GetInfo(); e != nil {
-		fmt.Printf("eeprom read failed: %s; using random address block", e)
-		p.BaseEthernetAddress = ethernet.RandomAddress()
-		p.NEthernetAddress = 256
-	} else {
-		p.BaseEthernetAddress = ethernet.Address(d.Fields.BaseEthernetAddress)
+		randomAddressBlock(p, fmt.Sprintf("eeprom read failed: %s", e))
+		return
+	}
+	p.Version = uint(d.Fields.DeviceVersion)
+	product = d.Fields.ProductName
+
+	// Refuse address blocks that would hand out bogus or someone else's addresses.
+	a := ethernet.Address(d.Fields.BaseEthernetAddress)
+	n := uint(d.Fields.NEthernetAddress)
+	low := uint(a[3])<<16 | uint(a[4])<<8 | uint(a[5])
+	switch {
+	case n == 0:
+		randomAddressBlock(p, "eeprom has no ethernet addresses")
+	case a == (ethernet.Address{}):
+		randomAddressBlock(p, "eeprom base ethernet address is zero")
+	case a[0]&1 != 0:
+		randomAddressBlock(p, fmt.Sprintf("eeprom base ethernet address %v is multicast", a))
+	case low+n > 1<<24:
+		randomAddressBlock(p, fmt.Sprintf("eeprom address block %v + %d overflows OUI", a, n))
+	default:
+		p.BaseEthernetAddress = a
 		p.NEthernetAddress = d.Fields.NEthernetAddress
-		p.Version = uint(d.Fields.DeviceVersion)
 	}
 }
 
+func randomAddressBlock(p *fe1.Platform, why string) {
+	fmt.Printf("%s; using random address block\n", why)
+	p.BaseEthernetAddress = ethernet.RandomAddress()
+	p.NEthernetAddress = 256
+}
+
 func main() {
 	var err error
 	defer func() {
@@ -43,8 +65,9 @@ func main() {
 	v := &vnet.Vnet{}
 	p := &fe1.Platform{}
 
-	platformConfigFromEEPROM(p)
+	product := platformConfigFromEEPROM(p)
 
+	boardName := ""
 	{
 		var wip_in parse.Input
 		cf := &p.PlatformConfig
@@ -56,6 +79,7 @@ func main() {
 		if in.Parse("wip %v", &wip_in) {
 			for !wip_in.End() {
 				switch {
+				case wip_in.Parse("board %s", &boardName):
 				case wip_in.Parse("gpio-reset"):
 					cf.DisableGpioSwitchReset = false
 				case wip_in.Parse("no-gpio-reset"):
@@ -80,13 +104,17 @@ func main() {
 		}
 	}
 
-	if err = mk1.PlatformInit(v, p); err != nil {
+	var b board
+	if b, err = selectBoard(boardName, product); err != nil {
+		return
+	}
+	if err = b.Init(v, p); err != nil {
 		return
 	}
 	if err = v.Run(&in); err != nil {
 		return
 	}
-	if err = mk1.PlatformExit(v, p); err != nil {
+	if err = b.Exit(v, p); err != nil {
 		return
 	}
 }